@@ -0,0 +1,160 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package gojobqueue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* JobContext describes a single job as it moves through a HookedQueue, and is passed to
+every Hooks callback for that job. StartedAt is the zero Time until OnStart fires, and
+Attempt starts at 1; a wrap.Retry wrapper around transact is invisible to the queue, so it
+does not advance Attempt itself.
+*/
+type JobContext struct {
+	ID         uint64
+	Name       string
+	EnqueuedAt time.Time
+	StartedAt  time.Time
+	Attempt    int
+}
+
+/* Hooks lets callers observe a job's lifecycle: when it is enqueued, when a worker starts
+it, and how it finished. Every field is optional; nil hooks are simply not called.
+*/
+type Hooks struct {
+	OnEnqueue       func(JobContext)
+	OnStart         func(JobContext)
+	OnSuccess       func(JobContext)
+	OnError         func(JobContext, error)
+	OnRollback      func(JobContext, error)
+	OnRollbackError func(JobContext, interface{})
+}
+
+/* HookedQueue wraps a MemoryQueue and fires Hooks around each job's transact and rollback,
+including JobContext.ID, EnqueuedAt and StartedAt, without requiring the caller to
+instrument their own job functions. It also adds AddJobCtx, which propagates a
+context.Context into transact for callers that want to attach e.g. an OpenTelemetry span.
+*/
+type HookedQueue struct {
+	q     MemoryQueue
+	Hooks Hooks
+
+	seq uint64
+
+	mu      sync.Mutex
+	cancels map[uint64]context.CancelFunc
+}
+
+// NewHookedQueue returns a HookedQueue that forwards jobs to q, firing hooks on the way.
+func NewHookedQueue(q MemoryQueue, hooks Hooks) *HookedQueue {
+	return &HookedQueue{q: q, Hooks: hooks, cancels: make(map[uint64]context.CancelFunc)}
+}
+
+// AddJob behaves like MemoryQueue.AddJob, but fires h.Hooks around transact and rollback.
+func (h *HookedQueue) AddJob(transact func() error, rollback func(error)) error {
+	wrappedTransact, wrappedRollback := h.wrap("", context.Background(), func(context.Context) error { return transact() }, rollback)
+	return h.q.AddJob(wrappedTransact, wrappedRollback)
+}
+
+/* AddJobCtx is like AddJob, but transact receives a context.Context that is cancelled when
+the HookedQueue is closed, so long-running jobs can hook cancellation, tracing spans, etc.
+into it.
+*/
+func (h *HookedQueue) AddJobCtx(ctx context.Context, name string, transact func(context.Context) error, rollback func(error)) error {
+	wrappedTransact, wrappedRollback := h.wrap(name, ctx, transact, rollback)
+	return h.q.AddJob(wrappedTransact, wrappedRollback)
+}
+
+func (h *HookedQueue) wrap(name string, ctx context.Context, transact func(context.Context) error, rollback func(error)) (func() error, func(error)) {
+	jc := JobContext{
+		ID:         atomic.AddUint64(&h.seq, 1),
+		Name:       name,
+		EnqueuedAt: time.Now(),
+		Attempt:    1,
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	h.mu.Lock()
+	h.cancels[jc.ID] = cancel
+	h.mu.Unlock()
+
+	if h.Hooks.OnEnqueue != nil {
+		h.Hooks.OnEnqueue(jc)
+	}
+
+	wrappedTransact := func() error {
+		defer func() {
+			h.mu.Lock()
+			delete(h.cancels, jc.ID)
+			h.mu.Unlock()
+			cancel()
+		}()
+
+		jc.StartedAt = time.Now()
+		if h.Hooks.OnStart != nil {
+			h.Hooks.OnStart(jc)
+		}
+		err := transact(jobCtx)
+		if err != nil {
+			if h.Hooks.OnError != nil {
+				h.Hooks.OnError(jc, err)
+			}
+		} else if h.Hooks.OnSuccess != nil {
+			h.Hooks.OnSuccess(jc)
+		}
+		return err
+	}
+
+	wrappedRollback := func(err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				if h.Hooks.OnRollbackError != nil {
+					h.Hooks.OnRollbackError(jc, r)
+				}
+			}
+		}()
+		rollback(err)
+		if h.Hooks.OnRollback != nil {
+			h.Hooks.OnRollback(jc, err)
+		}
+	}
+
+	return wrappedTransact, wrappedRollback
+}
+
+// StartWorking delegates to the underlying MemoryQueue.
+func (h *HookedQueue) StartWorking() {
+	h.q.StartWorking()
+}
+
+// Underlying returns the MemoryQueue that h forwards jobs to. It exists so a Pool can be
+// built with NewHookedPool, running its workers directly over h's channel while still being
+// able to reach h's AddJobCtx cancellation through AbortInFlight.
+func (h *HookedQueue) Underlying() MemoryQueue {
+	return h.q
+}
+
+// AbortInFlight cancels the context of every job added through AddJobCtx that is still
+// running, without closing the underlying MemoryQueue. Jobs that observe ctx.Done() can use
+// this to stop early; jobs added via plain AddJob, or that ignore their context, are
+// unaffected. Close calls this too; it is exported separately so a Pool can abort in-flight
+// jobs on a Shutdown timeout before the queue itself is closed.
+func (h *HookedQueue) AbortInFlight() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, cancel := range h.cancels {
+		cancel()
+	}
+}
+
+// Close aborts every in-flight job (see AbortInFlight), then delegates to the underlying
+// MemoryQueue.
+func (h *HookedQueue) Close() error {
+	h.AbortInFlight()
+	return h.q.Close()
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package gojobqueue
+
+import (
+	"context"
+	"sync"
+)
+
+/* StartWorkingN is like StartWorking, but launches n goroutines ranging over the Queue's
+channel instead of a single one, so up to n jobs can be worked on concurrently.
+*/
+func (q MemoryQueue) StartWorkingN(n int) {
+	for i := 0; i < n; i++ {
+		go workJobs(q)
+	}
+}
+
+/* A Pool runs n workers over a MemoryQueue and can be shut down gracefully, waiting for
+in-flight jobs to finish instead of abandoning them mid-transact.
+
+Create one with NewPool, call Start to begin working jobs, and call Shutdown or Wait when
+you're done adding jobs to the underlying Queue.
+*/
+type Pool struct {
+	q     MemoryQueue
+	abort func()
+	n     int
+	wg    sync.WaitGroup
+}
+
+// NewPool returns a Pool that will run n workers over q once Start is called.
+func NewPool(q MemoryQueue, n int) *Pool {
+	return &Pool{q: q, n: n}
+}
+
+/* NewHookedPool is like NewPool, but runs the pool's workers over hq's underlying
+MemoryQueue and wires Shutdown's abort path into hq: if ctx expires before the in-flight
+jobs finish, Shutdown calls hq.AbortInFlight, cancelling the context of every job added
+through hq.AddJobCtx. Jobs that check ctx.Done() can then actually stop; jobs added via
+plain AddJob, or that ignore their context, cannot be forcibly killed at this layer and are
+left running, same as with NewPool.
+*/
+func NewHookedPool(hq *HookedQueue, n int) *Pool {
+	return &Pool{q: hq.Underlying(), abort: hq.AbortInFlight, n: n}
+}
+
+// Start launches the pool's workers. It does not block.
+func (p *Pool) Start() {
+	p.wg.Add(p.n)
+	for i := 0; i < p.n; i++ {
+		go func() {
+			defer p.wg.Done()
+			workJobs(p.q)
+		}()
+	}
+}
+
+/* Wait blocks until every worker has returned, which happens once the underlying Queue is
+closed and all pending jobs have been worked. Closing the Queue is the caller's
+responsibility.
+*/
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+/* Shutdown closes the Queue, so no new jobs can be added, and waits for the in-flight and
+still-pending jobs to finish. If ctx expires first, Shutdown calls its abort hook, if one
+was wired up via NewHookedPool, and returns ctx.Err() without waiting any further. A Pool
+created with plain NewPool has no abort hook: there is no way to forcibly stop a bare
+func() error, so its workers are simply left running and will finish on their own.
+*/
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.q.Close()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if p.abort != nil {
+			p.abort()
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package gojobqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPoolShutdownWaitsForInFlightJob(t *testing.T) {
+	q := make(MemoryQueue, 1)
+	pool := NewPool(q, 1)
+	pool.Start()
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	q.AddJob(func() error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+		return nil
+	}, nil)
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Shutdown returned before the in-flight job finished")
+	}
+}
+
+func TestPoolShutdownAbortsHookedJobOnCtxExpiry(t *testing.T) {
+	q := make(MemoryQueue, 1)
+	hq := NewHookedQueue(q, Hooks{})
+	pool := NewHookedPool(hq, 1)
+	pool.Start()
+
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	hq.AddJobCtx(context.Background(), "slow", func(ctx context.Context) error {
+		close(started)
+		select {
+		case <-ctx.Done():
+			close(aborted)
+		case <-time.After(time.Second):
+		}
+		return ctx.Err()
+	}, nil)
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown() = nil, want a context deadline error")
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not cancel the in-flight AddJobCtx job's context")
+	}
+}
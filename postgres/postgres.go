@@ -0,0 +1,229 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+/* Package postgres provides a PostgreSQL-backed job queue. Jobs are persisted as
+rows in a table, so they survive a process restart, and can be added by producers on any
+host, even in another language, as long as they insert matching rows. Multiple Go worker
+processes can safely StartWorking against the same database: each claims a row with
+pg_try_advisory_lock before running it, so a job is never run twice.
+
+Usage
+
+Create the jobs table using Schema, then open a PostgresQueue with New. Register a handler
+for every job name you expect to see with RegisterJob before calling StartWorking. Producers
+insert a row into the jobs table (name, args) themselves; this package does not provide an
+AddJob, since a PostgresQueue's jobs usually aren't added from Go at all.
+*/
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+
+	// fallbackPollInterval is how often work polls the table even without a NOTIFY, as a
+	// safety net for notifications a worker missed (e.g. while reconnecting).
+	fallbackPollInterval = 30 * time.Second
+)
+
+const notifyChannel = "gojobqueue_jobs"
+
+// Schema is the DDL for the table and trigger PostgresQueue expects. Run it once, e.g. via
+// a migration, before using a PostgresQueue against a fresh database. The trigger calls
+// pg_notify on every insert, which is what lets StartWorking pick up new rows immediately
+// instead of waiting for its fallback poll.
+var Schema = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS gojobqueue_jobs (
+	id         BIGSERIAL PRIMARY KEY,
+	name       TEXT NOT NULL,
+	args       JSONB NOT NULL DEFAULT '{}',
+	failed     BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION gojobqueue_notify() RETURNS trigger AS $body$
+BEGIN
+	PERFORM pg_notify('%[1]s', NEW.id::text);
+	RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS gojobqueue_jobs_notify ON gojobqueue_jobs;
+CREATE TRIGGER gojobqueue_jobs_notify
+	AFTER INSERT ON gojobqueue_jobs
+	FOR EACH ROW EXECUTE PROCEDURE gojobqueue_notify();
+`, notifyChannel)
+
+// ErrNotRegistered is logged when claimAndRun finds a row whose name has no handler
+// registered via RegisterJob. The row is marked failed so it isn't reclaimed and relogged
+// forever; StartWorking runs in the background, so there is no caller to return it to.
+var ErrNotRegistered = errors.New("postgres: job name not registered")
+
+/* PostgresQueue is a job queue backed by a PostgreSQL table. It has gojobqueue.Queue's
+StartWorking and Close, but unlike MemoryQueue it has no AddJob (see the package doc for
+why), so it does not satisfy the gojobqueue.Queue interface.
+*/
+type PostgresQueue struct {
+	db       *sql.DB
+	listener *pq.Listener
+
+	mu       sync.RWMutex
+	registry map[string]func(json.RawMessage) error
+
+	done chan struct{}
+}
+
+// New opens a PostgresQueue against dsn. The caller is responsible for having applied
+// Schema to that database beforehand.
+func New(dsn string) (*PostgresQueue, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: open: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("postgres: ping: %w", err)
+	}
+	listener := pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, nil)
+	if err := listener.Listen(notifyChannel); err != nil {
+		return nil, fmt.Errorf("postgres: listen: %w", err)
+	}
+	return &PostgresQueue{
+		db:       db,
+		listener: listener,
+		registry: make(map[string]func(json.RawMessage) error),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// RegisterJob associates name with fn, so that rows inserted with that name are run by fn
+// once claimed. Register every job name before calling StartWorking.
+func (q *PostgresQueue) RegisterJob(name string, fn func(json.RawMessage) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.registry[name] = fn
+}
+
+func (q *PostgresQueue) lookup(name string) (func(json.RawMessage) error, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	fn, ok := q.registry[name]
+	return fn, ok
+}
+
+/* StartWorking begins claiming and running rows from the jobs table. It polls once
+immediately to pick up anything already pending, then reacts to LISTEN/NOTIFY so newly
+inserted rows (via the trigger installed by Schema) are picked up right away, falling back
+to a poll every fallbackPollInterval in case a notification was ever missed.
+*/
+func (q *PostgresQueue) StartWorking() {
+	go q.work()
+}
+
+func (q *PostgresQueue) work() {
+	q.poll()
+
+	ticker := time.NewTicker(fallbackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		case n := <-q.listener.Notify:
+			if n != nil {
+				q.poll()
+			}
+		case <-ticker.C:
+			q.poll()
+		}
+	}
+}
+
+// poll claims and runs every pending row it can get an advisory lock on.
+func (q *PostgresQueue) poll() {
+	rows, err := q.db.Query(`SELECT id, name, args FROM gojobqueue_jobs WHERE NOT failed ORDER BY id`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var claimed []int64
+	var names []string
+	var argsList []json.RawMessage
+	for rows.Next() {
+		var id int64
+		var name string
+		var args json.RawMessage
+		if err := rows.Scan(&id, &name, &args); err != nil {
+			continue
+		}
+		claimed = append(claimed, id)
+		names = append(names, name)
+		argsList = append(argsList, args)
+	}
+
+	for i, id := range claimed {
+		q.claimAndRun(id, names[i], argsList[i])
+	}
+}
+
+/* claimAndRun acquires the advisory lock for id, runs its handler and releases the lock,
+all on the same pinned *sql.Conn. pg_try_advisory_lock/pg_advisory_unlock are scoped to the
+session (physical connection) that took them, so running the acquire and release through
+*sql.DB directly would risk borrowing two different pooled connections and leaking the lock
+on whichever connection actually holds it.
+*/
+func (q *PostgresQueue) claimAndRun(id int64, name string, args json.RawMessage) {
+	ctx := context.Background()
+	conn, err := q.db.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, id).Scan(&locked); err != nil || !locked {
+		return
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, id)
+
+	// Another worker may have run and deleted (or failed) the row since poll read it.
+	var pending bool
+	if err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM gojobqueue_jobs WHERE id = $1 AND NOT failed)`, id).Scan(&pending); err != nil || !pending {
+		return
+	}
+
+	fn, ok := q.lookup(name)
+	if !ok {
+		log.Printf("postgres: job %d: %v: %q", id, ErrNotRegistered, name)
+		q.db.Exec(`UPDATE gojobqueue_jobs SET failed = TRUE WHERE id = $1`, id)
+		return
+	}
+
+	if err := fn(args); err != nil {
+		q.db.Exec(`UPDATE gojobqueue_jobs SET failed = TRUE WHERE id = $1`, id)
+		return
+	}
+	q.db.Exec(`DELETE FROM gojobqueue_jobs WHERE id = $1`, id)
+}
+
+// Close stops StartWorking's worker goroutine and releases the database connections.
+func (q *PostgresQueue) Close() error {
+	close(q.done)
+	if err := q.listener.Close(); err != nil {
+		return err
+	}
+	return q.db.Close()
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSchemaInstallsNotifyTrigger guards against the notify trigger regressing back to a
+// no-op: Schema must both call pg_notify on notifyChannel and install a trigger that fires
+// it on insert, or StartWorking has nothing to react to besides its fallback poll.
+func TestSchemaInstallsNotifyTrigger(t *testing.T) {
+	if !strings.Contains(Schema, fmt.Sprintf("pg_notify('%s'", notifyChannel)) {
+		t.Fatalf("Schema does not call pg_notify with notifyChannel:\n%s", Schema)
+	}
+	if !strings.Contains(Schema, "CREATE TRIGGER") || !strings.Contains(Schema, "AFTER INSERT") {
+		t.Fatalf("Schema does not install an AFTER INSERT trigger:\n%s", Schema)
+	}
+}
+
+// TestRegisterJobAndLookupConcurrent exercises the registry without needing a live
+// database: RegisterJob/lookup only touch PostgresQueue.mu and .registry.
+func TestRegisterJobAndLookupConcurrent(t *testing.T) {
+	q := &PostgresQueue{registry: make(map[string]func(json.RawMessage) error)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("job-%d", i)
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			q.RegisterJob(name, func(json.RawMessage) error { return nil })
+		}(name)
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("job-%d", i)
+		if _, ok := q.lookup(name); !ok {
+			t.Fatalf("lookup(%q) not found after concurrent RegisterJob", name)
+		}
+	}
+	if _, ok := q.lookup("never-registered"); ok {
+		t.Fatal(`lookup("never-registered") = true, want false`)
+	}
+}
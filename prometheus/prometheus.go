@@ -0,0 +1,76 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+/* Package prometheus exposes a gojobqueue.Hooks value that records job lifecycle events as
+Prometheus metrics: jobs_total (a counter, by status), job_duration_seconds and
+queue_wait_seconds (histograms), and queue_pending_jobs (a gauge tracking a MemoryQueue's
+current length).
+
+Usage
+
+	q := make(gojobqueue.MemoryQueue, 20)
+	hq := gojobqueue.NewHookedQueue(q, prometheus.Hooks())
+	prom.MustRegister(prometheus.PendingGauge(q))
+*/
+package prometheus
+
+import (
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/p-weisk/gojobqueue"
+)
+
+var (
+	jobsTotal = prom.NewCounterVec(prom.CounterOpts{
+		Name: "jobs_total",
+		Help: "Total number of jobs processed, by status (success, error, rollback_panic).",
+	}, []string{"status"})
+
+	jobDuration = prom.NewHistogram(prom.HistogramOpts{
+		Name: "job_duration_seconds",
+		Help: "Time spent running a job's transact function.",
+	})
+
+	queueWait = prom.NewHistogram(prom.HistogramOpts{
+		Name: "queue_wait_seconds",
+		Help: "Time a job spent waiting in the queue between being enqueued and started.",
+	})
+)
+
+func init() {
+	prom.MustRegister(jobsTotal, jobDuration, queueWait)
+}
+
+// PendingGauge returns a gauge tracking q's current queue depth (len(q)). Register it with
+// a prometheus.Registerer of your choice.
+func PendingGauge(q gojobqueue.MemoryQueue) prom.GaugeFunc {
+	return prom.NewGaugeFunc(prom.GaugeOpts{
+		Name: "queue_pending_jobs",
+		Help: "Number of jobs currently pending in the queue.",
+	}, func() float64 {
+		return float64(len(q))
+	})
+}
+
+// Hooks returns a gojobqueue.Hooks that records jobs_total, job_duration_seconds and
+// queue_wait_seconds as jobs move through a HookedQueue's lifecycle.
+func Hooks() gojobqueue.Hooks {
+	return gojobqueue.Hooks{
+		OnStart: func(jc gojobqueue.JobContext) {
+			queueWait.Observe(jc.StartedAt.Sub(jc.EnqueuedAt).Seconds())
+		},
+		OnSuccess: func(jc gojobqueue.JobContext) {
+			jobDuration.Observe(time.Since(jc.StartedAt).Seconds())
+			jobsTotal.WithLabelValues("success").Inc()
+		},
+		OnError: func(jc gojobqueue.JobContext, err error) {
+			jobDuration.Observe(time.Since(jc.StartedAt).Seconds())
+			jobsTotal.WithLabelValues("error").Inc()
+		},
+		OnRollbackError: func(jc gojobqueue.JobContext, r interface{}) {
+			jobsTotal.WithLabelValues("rollback_panic").Inc()
+		},
+	}
+}
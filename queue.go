@@ -6,29 +6,44 @@ Jobs consist of one function that will be executed, and one function that will b
 
 Usage
 
-First, create a new Queue with a buffer size using the make function. If you do not use a buffer, Adding Jobs will block until the Queue is empty, rendering it rather useless.
+First, create a new MemoryQueue with a buffer size using the make function. If you do not use a buffer, Adding Jobs will block until the Queue is empty, rendering it rather useless.
 You can Add jobs to it at any time using AddJob.
 Whenever you are ready to start executing Jobs, call StartWorking. You can still add jobs afterwards.
 When you're done with the Queue, you can call Close on it. This will close the underlying channel.
 */
 package gojobqueue
 
-/* Can be created like a channel, using make with a buffer size:
-	q := make(Queue, 20) // q is a Queue that can hold a maximum of 20 pending jobs at a time.
+/* Queue is implemented by the job queue backends in this module that can both add and work
+jobs from Go, such as the in-process MemoryQueue. The postgres subpackage's PostgresQueue
+has StartWorking and Close but no AddJob, so it does not satisfy Queue. Code that only
+needs to add jobs and work them should depend on Queue rather than a concrete backend.
 */
-type Queue chan job
+type Queue interface {
+	// AddJob adds a Job to the Queue. See MemoryQueue.AddJob for the argument semantics.
+	AddJob(transact func() error, rollback func(error)) error
+	// StartWorking starts executing the jobs already in the Queue, and any new Jobs added to it.
+	StartWorking()
+	// Close stops the Queue from accepting further jobs.
+	Close() error
+}
 
 type job struct {
 	transact func() error
 	rollback func(error)
 }
 
+/* MemoryQueue is the default, in-process Queue implementation. Can be created like a
+channel, using make with a buffer size:
+	q := make(MemoryQueue, 20) // q is a MemoryQueue that can hold a maximum of 20 pending jobs at a time.
+*/
+type MemoryQueue chan job
+
 /* Adds a Job to the Queue it is called on. Takes two arguments:
 	transact func() error // The function that should contain a Job's logic.
 	rollback func(error) // The function will only be called if transact() returns an error, with this error as an argument. Can be used for rolling back changes, doing cleanups, error logging etc.
 Returns an error if the Queue is already closed (so unlike channels, it will not panic).
  */
-func (q Queue) AddJob(transact func() error, rollback func(error)) (err error) {
+func (q MemoryQueue) AddJob(transact func() error, rollback func(error)) (err error) {
 	j := job{transact, rollback}
 	defer func() {
 		r := recover()
@@ -41,20 +56,21 @@ func (q Queue) AddJob(transact func() error, rollback func(error)) (err error) {
 }
 
 // Closes the underlying channel.
-func (q Queue) Close() {
+func (q MemoryQueue) Close() error {
 	close(q)
+	return nil
 }
 
 // Starts executing the jobs already in the Queue, and any new Jobs you add to it.
-func (q Queue) StartWorking() {
+func (q MemoryQueue) StartWorking() {
 	go workJobs(q)
 }
 
-func workJobs(q Queue) {
+func workJobs(q MemoryQueue) {
 	for j := range q {
 		err := j.transact()
 		if err != nil {
 			j.rollback(err)
 		}
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package gojobqueue
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+/* A Scheduler adds priority and delayed/scheduled jobs on top of a plain MemoryQueue.
+Jobs added through AddJobAt or AddJobIn are held in an internal min-heap, keyed by
+(runAt, priority, insertion order), until they become due, at which point a single
+dispatcher goroutine forwards them to the underlying MemoryQueue for a worker to pick up.
+Jobs added directly to the MemoryQueue via AddJob are unaffected and keep running in plain
+FIFO order; among jobs scheduled for the same instant with the same priority, AddJobAt and
+AddJobIn preserve FIFO order too.
+*/
+type Scheduler struct {
+	q MemoryQueue
+
+	mu   sync.Mutex
+	h    jobHeap
+	seq  uint64
+	wake chan struct{}
+}
+
+// NewScheduler returns a Scheduler that forwards due jobs to q. Call Start to begin
+// dispatching.
+func NewScheduler(q MemoryQueue) *Scheduler {
+	return &Scheduler{q: q, wake: make(chan struct{}, 1)}
+}
+
+/* AddJobAt schedules a job to be forwarded to the underlying MemoryQueue no earlier than
+runAt. Among jobs due at the same time, higher priority values are forwarded first.
+*/
+func (s *Scheduler) AddJobAt(runAt time.Time, priority int, transact func() error, rollback func(error)) error {
+	s.mu.Lock()
+	s.seq++
+	heap.Push(&s.h, &heapItem{
+		runAt:    runAt,
+		priority: priority,
+		seq:      s.seq,
+		j:        job{transact, rollback},
+	})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// AddJobIn schedules a job to be forwarded to the underlying MemoryQueue no earlier than
+// delay from now. See AddJobAt for priority semantics.
+func (s *Scheduler) AddJobIn(delay time.Duration, priority int, transact func() error, rollback func(error)) error {
+	return s.AddJobAt(time.Now().Add(delay), priority, transact, rollback)
+}
+
+// Start launches the dispatcher goroutine. It does not block.
+func (s *Scheduler) Start() {
+	go s.dispatch()
+}
+
+func (s *Scheduler) dispatch() {
+	for {
+		s.mu.Lock()
+		if len(s.h) == 0 {
+			s.mu.Unlock()
+			<-s.wake
+			continue
+		}
+		wait := time.Until(s.h[0].runAt)
+		if wait <= 0 {
+			item := heap.Pop(&s.h).(*heapItem)
+			s.mu.Unlock()
+			s.q.AddJob(item.j.transact, item.j.rollback)
+			continue
+		}
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+type heapItem struct {
+	runAt    time.Time
+	priority int
+	seq      uint64
+	j        job
+}
+
+// jobHeap is a container/heap.Interface ordered by runAt, then by descending priority,
+// then by insertion order, so it behaves as a min-heap on "what's due next".
+type jobHeap []*heapItem
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if !h[i].runAt.Equal(h[j].runAt) {
+		return h[i].runAt.Before(h[j].runAt)
+	}
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) {
+	*h = append(*h, x.(*heapItem))
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package gojobqueue
+
+import (
+	"container/heap"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobHeapOrdering(t *testing.T) {
+	now := time.Now()
+	h := &jobHeap{}
+	heap.Init(h)
+
+	// Same runAt, same priority: FIFO by seq (1 before 2).
+	heap.Push(h, &heapItem{runAt: now, priority: 0, seq: 1})
+	heap.Push(h, &heapItem{runAt: now, priority: 0, seq: 2})
+	// Same runAt, higher priority: jumps ahead of both.
+	heap.Push(h, &heapItem{runAt: now, priority: 5, seq: 3})
+	// Later runAt: last, despite the highest priority.
+	heap.Push(h, &heapItem{runAt: now.Add(time.Hour), priority: 100, seq: 4})
+
+	var order []uint64
+	for h.Len() > 0 {
+		order = append(order, heap.Pop(h).(*heapItem).seq)
+	}
+
+	want := []uint64{3, 1, 2, 4}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSchedulerDispatchesByRunAtThenPriority(t *testing.T) {
+	q := make(MemoryQueue, 10)
+	sched := NewScheduler(q)
+	sched.Start()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(label string) func() error {
+		return func() error {
+			mu.Lock()
+			order = append(order, label)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	now := time.Now()
+	sched.AddJobAt(now.Add(60*time.Millisecond), 0, record("late-low"), nil)
+	sched.AddJobAt(now.Add(20*time.Millisecond), 5, record("early-high"), nil)
+	sched.AddJobAt(now.Add(20*time.Millisecond), 0, record("early-low"), nil)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			j := <-q
+			j.transact()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for all scheduled jobs to dispatch")
+	}
+
+	want := []string{"early-high", "early-low", "late-low"}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+/* Package wrap provides composable wrappers around a gojobqueue job's transact function.
+Wrappers are plain `func(JobFunc) JobFunc` values, so they can be chained with Wrap before
+the result is handed to Queue.AddJob:
+
+	q.AddJob(wrap.Wrap(myFn, wrap.Retry(3, wrap.Expo(100*time.Millisecond)), wrap.Timeout(5*time.Second)), rollback)
+
+Wrappers are applied in the order they are listed, i.e. the first wrapper is the outermost
+one: in the example above Retry sees the result of Timeout, so each retry attempt is itself
+subject to the timeout.
+*/
+package wrap
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JobFunc is the shape of the transact function a Queue job runs.
+type JobFunc func() error
+
+// ErrTimeout is returned by Timeout and Deadline when next did not return before the
+// deadline elapsed. Retry treats it like any other error and retries it.
+var ErrTimeout = errors.New("wrap: job timed out")
+
+// ErrDuplicate is returned by Unique when a job with the same key is already pending.
+var ErrDuplicate = errors.New("wrap: duplicate job key")
+
+// Wrap applies wrappers to fn, the first wrapper listed ending up outermost, and returns
+// the resulting JobFunc ready to be passed to Queue.AddJob.
+func Wrap(fn JobFunc, wrappers ...func(JobFunc) JobFunc) JobFunc {
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		fn = wrappers[i](fn)
+	}
+	return fn
+}
+
+// BackoffFunc returns how long to wait before the given retry attempt (1-based).
+type BackoffFunc func(attempt int) time.Duration
+
+// Constant always waits d between attempts.
+func Constant(d time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return d
+	}
+}
+
+// Linear waits step*attempt between attempts.
+func Linear(step time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return step * time.Duration(attempt)
+	}
+}
+
+// Expo waits base*2^(attempt-1), plus up to 50% random jitter, between attempts.
+func Expo(base time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base << uint(attempt-1)
+		jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+		return d + jitter
+	}
+}
+
+// Retry calls next up to n times, sleeping according to backoff between attempts, and
+// returns nil as soon as an attempt succeeds. If every attempt fails it returns the error
+// from the last attempt, so a rollback passed to AddJob only fires once retries are
+// exhausted.
+func Retry(n int, backoff BackoffFunc) func(JobFunc) JobFunc {
+	return func(next JobFunc) JobFunc {
+		return func() error {
+			var err error
+			for attempt := 1; attempt <= n; attempt++ {
+				if err = next(); err == nil {
+					return nil
+				}
+				if attempt < n {
+					time.Sleep(backoff(attempt))
+				}
+			}
+			return err
+		}
+	}
+}
+
+// Timeout runs next in a goroutine and returns ErrTimeout if it has not finished after d.
+// next keeps running in the background even after Timeout has given up on it.
+func Timeout(d time.Duration) func(JobFunc) JobFunc {
+	return func(next JobFunc) JobFunc {
+		return func() error {
+			done := make(chan error, 1)
+			go func() {
+				done <- next()
+			}()
+			select {
+			case err := <-done:
+				return err
+			case <-time.After(d):
+				return ErrTimeout
+			}
+		}
+	}
+}
+
+/* Deadline is like Timeout, but expressed as an absolute point in time rather than a
+duration. The time remaining until t is computed when the job actually runs, not when
+Deadline wraps it, so a deadline is still honored correctly for a job that sits in a queue
+for a while before a worker picks it up. A deadline already in the past fails immediately
+with ErrTimeout, without calling next.
+*/
+func Deadline(t time.Time) func(JobFunc) JobFunc {
+	return func(next JobFunc) JobFunc {
+		return func() error {
+			d := time.Until(t)
+			if d <= 0 {
+				return ErrTimeout
+			}
+			return Timeout(d)(next)()
+		}
+	}
+}
+
+// Delay waits d before calling next.
+func Delay(d time.Duration) func(JobFunc) JobFunc {
+	return func(next JobFunc) JobFunc {
+		return func() error {
+			time.Sleep(d)
+			return next()
+		}
+	}
+}
+
+// UniqueSet tracks the keys of jobs currently pending through Unique. Create one with
+// NewUniqueSet and share it between all Unique wrappers that should dedupe against
+// each other.
+type UniqueSet struct {
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+// NewUniqueSet returns an empty UniqueSet.
+func NewUniqueSet() *UniqueSet {
+	return &UniqueSet{pending: make(map[string]struct{})}
+}
+
+func (s *UniqueSet) claim(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[key]; ok {
+		return false
+	}
+	s.pending[key] = struct{}{}
+	return true
+}
+
+func (s *UniqueSet) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, key)
+}
+
+/* Unique rejects a job with ErrDuplicate if a job with the same key, registered against the
+same set, is already pending. The key is claimed as soon as Unique wraps next, i.e. when
+Wrap composes the job right before it is handed to AddJob, not when a worker eventually runs
+it — so two jobs sharing a key that are both enqueued before either has run correctly
+reject the second one, rather than letting both run to completion under a single-worker
+Queue. The key is released once next returns, regardless of outcome.
+*/
+func Unique(set *UniqueSet, key string) func(JobFunc) JobFunc {
+	return func(next JobFunc) JobFunc {
+		if !set.claim(key) {
+			return func() error {
+				return ErrDuplicate
+			}
+		}
+		return func() error {
+			defer set.release(key)
+			return next()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2018 Paul Weiske
+//This Code is licensed under the MIT License, see LICENSE file for details.
+
+package wrap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUniqueRejectsKeyClaimedAtEnqueueTime(t *testing.T) {
+	set := NewUniqueSet()
+	var ran []string
+
+	record := func(label string) JobFunc {
+		return func() error {
+			ran = append(ran, label)
+			return nil
+		}
+	}
+
+	// Both jobs are wrapped (i.e. "enqueued") back-to-back, before either has run, the way
+	// two AddJob calls sharing a key would be.
+	a := Wrap(record("A"), Unique(set, "same-key"))
+	b := Wrap(record("B"), Unique(set, "same-key"))
+
+	if err := a(); err != nil {
+		t.Fatalf("a() = %v, want nil", err)
+	}
+	if err := b(); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("b() = %v, want ErrDuplicate", err)
+	}
+
+	if want := []string{"A"}; len(ran) != 1 || ran[0] != want[0] {
+		t.Fatalf("ran = %v, want %v", ran, want)
+	}
+
+	// Once a's key is released, the same key can be claimed again.
+	c := Wrap(record("C"), Unique(set, "same-key"))
+	if err := c(); err != nil {
+		t.Fatalf("c() = %v, want nil", err)
+	}
+}